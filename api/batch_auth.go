@@ -0,0 +1,13 @@
+package api
+
+// BatchIsAuthorizedRequest carries a list of authorization checks to evaluate together.
+// It mirrors IsAuthorizedRequest but allows many checks to be sent in a single RPC so
+// clients rendering a screen with many guarded resources don't need one round trip each.
+type BatchIsAuthorizedRequest struct {
+	IsAuthorized []*IsAuthorized
+}
+
+// BatchIsAuthorizedResponse returns one result per request item, in the same order.
+type BatchIsAuthorizedResponse struct {
+	Results []*IsAuthorizedResponse
+}
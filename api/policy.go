@@ -0,0 +1,47 @@
+package api
+
+// Rule is the wire representation of a fine-grained authorization rule.
+// SubjectKind and Effect are sent as their string form ("user"/"group"/"service-account"
+// and "allow"/"deny" respectively).
+type Rule struct {
+	Uid              string
+	Namespace        string
+	SubjectKind      string
+	SubjectName      string
+	ResourceType     string
+	ResourceNameGlob string
+	Verbs            []string
+	Effect           string
+}
+
+// CreateRuleRequest creates a new authorization rule in a namespace.
+type CreateRuleRequest struct {
+	Namespace string
+	Rule      *Rule
+}
+
+// CreateRuleResponse returns the created rule, including its generated Uid.
+type CreateRuleResponse struct {
+	Rule *Rule
+}
+
+// ListRulesRequest lists every rule configured for a namespace.
+type ListRulesRequest struct {
+	Namespace string
+}
+
+// ListRulesResponse returns the namespace's rules.
+type ListRulesResponse struct {
+	Rules []*Rule
+}
+
+// DeleteRuleRequest deletes a single rule by Uid.
+type DeleteRuleRequest struct {
+	Namespace string
+	Uid       string
+}
+
+// DeleteRuleResponse confirms whether the rule was deleted.
+type DeleteRuleResponse struct {
+	Deleted bool
+}
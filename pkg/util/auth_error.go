@@ -0,0 +1,77 @@
+package util
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// AuthErrorCode is a machine-readable reason for an AuthError, independent of
+// whatever transport (gRPC, HTTP, ...) eventually carries it.
+type AuthErrorCode string
+
+const (
+	AuthErrorValidationFailed AuthErrorCode = "VALIDATION_FAILED"
+	AuthErrorNoPermission     AuthErrorCode = "NO_PERMISSION"
+	AuthErrorUnauthenticated  AuthErrorCode = "UNAUTHENTICATED"
+	AuthErrorNotFound         AuthErrorCode = "NOT_FOUND"
+	AuthErrorAlreadyExists    AuthErrorCode = "ALREADY_EXISTS"
+	AuthErrorConflict         AuthErrorCode = "CONFLICT"
+	AuthErrorDeadlineExceeded AuthErrorCode = "DEADLINE_EXCEEDED"
+	AuthErrorBadInput         AuthErrorCode = "BAD_INPUT"
+	AuthErrorUnimplemented    AuthErrorCode = "UNIMPLEMENTED"
+	AuthErrorInternal         AuthErrorCode = "INTERNAL"
+	AuthErrorExternal         AuthErrorCode = "EXTERNAL"
+)
+
+// AuthError is a typed authorization/authentication error. It carries a Code that
+// callers can branch on instead of parsing Message, plus Metadata describing which
+// resource the error relates to, and a stack trace (via github.com/pkg/errors) for
+// server-side logging.
+type AuthError struct {
+	Code     AuthErrorCode
+	Message  string
+	Metadata map[string]string
+	cause    error
+}
+
+// NewAuthError creates an AuthError, capturing a stack trace at the call site.
+func NewAuthError(code AuthErrorCode, message string, metadata map[string]string) *AuthError {
+	return &AuthError{
+		Code:     code,
+		Message:  message,
+		Metadata: metadata,
+		cause:    errors.New(message),
+	}
+}
+
+// WrapAuthError wraps an existing error as an AuthError, preserving its stack trace
+// if it has one (e.g. it was produced by github.com/pkg/errors).
+func WrapAuthError(code AuthErrorCode, err error, metadata map[string]string) *AuthError {
+	return &AuthError{
+		Code:     code,
+		Message:  err.Error(),
+		Metadata: metadata,
+		cause:    errors.WithStack(err),
+	}
+}
+
+// Error implements the error interface.
+func (e *AuthError) Error() string {
+	return e.Message
+}
+
+// Unwrap allows errors.Is/errors.As to see through to the underlying cause.
+func (e *AuthError) Unwrap() error {
+	return e.cause
+}
+
+// Format implements fmt.Formatter so that "%+v" prints the stack trace captured at
+// the error's origin, the same way github.com/pkg/errors errors do.
+func (e *AuthError) Format(s fmt.State, verb rune) {
+	if formatter, ok := e.cause.(fmt.Formatter); ok && verb == 'v' && s.Flag('+') {
+		formatter.Format(s, verb)
+		return
+	}
+	_, _ = s.Write([]byte(e.Message))
+}
@@ -0,0 +1,49 @@
+// Package auth contains helpers for checking authorization of resources
+// against the Kubernetes API.
+package auth
+
+import (
+	v1 "github.com/onepanelio/core/pkg"
+	authorizationv1 "k8s.io/api/authorization/v1"
+)
+
+// IsAuthorized checks if the client is authorized to perform the verb on the resource.
+// An empty namespace, group, resource or resourceName means that check is skipped by the API server.
+// It consults the authorization cache before issuing a SelfSubjectAccessReview against k8s.
+func IsAuthorized(client *v1.Client, namespace, verb, group, resource, resourceName string) (allowed bool, err error) {
+	key := cacheKey(client.Token, namespace, verb, group, resource, resourceName)
+	if cached, ok := authCache.get(key); ok {
+		return cached, nil
+	}
+
+	allowed, err = checkAccess(client, namespace, verb, group, resource, resourceName)
+	if err != nil {
+		return false, err
+	}
+
+	authCache.set(key, allowed)
+
+	return allowed, nil
+}
+
+// checkAccess issues a single SelfSubjectAccessReview against the k8s API.
+func checkAccess(client *v1.Client, namespace, verb, group, resource, resourceName string) (bool, error) {
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      verb,
+				Group:     group,
+				Resource:  resource,
+				Name:      resourceName,
+			},
+		},
+	}
+
+	resp, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(review)
+	if err != nil {
+		return false, err
+	}
+
+	return resp.Status.Allowed, nil
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"sync"
+)
+
+// batchWorkerCount bounds how many SelfSubjectAccessReview calls are in flight at once
+// for a single BatchIsAuthorized call, so a large batch can't overwhelm the k8s API server.
+const batchWorkerCount = 8
+
+// AuthorizationCheck is a single (namespace, verb, group, resource, resourceName) tuple to evaluate.
+type AuthorizationCheck struct {
+	Namespace    string
+	Verb         string
+	Group        string
+	Resource     string
+	ResourceName string
+}
+
+// AuthorizationResult is the outcome of evaluating a single AuthorizationCheck.
+type AuthorizationResult struct {
+	Allowed bool
+	Error   error
+}
+
+// BatchIsAuthorized evaluates many authorization checks concurrently by calling
+// evaluate for each one, bounded by a worker pool so a large batch can't overwhelm
+// whatever evaluate ends up calling (e.g. the k8s API server). Results are returned
+// in the same order as checks.
+//
+// evaluate should apply the exact same decision the single-item IsAuthorized RPC
+// would for that check - policy rules and the PERMITTED_GROUPS allow-list included,
+// not just the bare SAR/cache check - so a client can't get a different answer by
+// routing a check through the batch RPC instead of the single one.
+func BatchIsAuthorized(checks []AuthorizationCheck, evaluate func(AuthorizationCheck) (bool, error)) []AuthorizationResult {
+	results := make([]AuthorizationResult, len(checks))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+
+	workers := batchWorkerCount
+	if workers > len(checks) {
+		workers = len(checks)
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				allowed, err := evaluate(checks[i])
+				results[i] = AuthorizationResult{Allowed: allowed, Error: err}
+			}
+		}()
+	}
+
+	for i := range checks {
+		indexes <- i
+	}
+	close(indexes)
+
+	wg.Wait()
+
+	return results
+}
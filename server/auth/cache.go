@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// authCacheTTL is how long a cached authorization decision is considered fresh.
+// It is intentionally short since permissions can change (e.g. role binding edits)
+// and we never want to serve a stale "allowed" decision for much longer than this.
+var authCacheTTL = 30 * time.Second
+
+// authCacheMaxEntries bounds the number of decisions authorizationCache holds. Once
+// full, the least-recently-used entry is evicted to make room for a new one, so the
+// cache can't grow without bound under varied (namespace, verb, resource, resourceName)
+// traffic.
+const authCacheMaxEntries = 10000
+
+// authCache is the process-wide cache consulted by IsAuthorized and BatchIsAuthorized.
+var authCache = newAuthorizationCache()
+
+// cacheMetrics tracks hit/miss counts for the authorization cache.
+// It is intentionally a simple counter rather than a full metrics client so it
+// has no dependency on whichever metrics backend is wired up elsewhere.
+type cacheMetrics struct {
+	mux    sync.Mutex
+	hits   uint64
+	misses uint64
+}
+
+func (m *cacheMetrics) recordHit() {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.hits++
+}
+
+func (m *cacheMetrics) recordMiss() {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.misses++
+}
+
+// Snapshot returns the current hit/miss counts.
+func (m *cacheMetrics) Snapshot() (hits, misses uint64) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.hits, m.misses
+}
+
+// CacheMetrics exposes the hit/miss counters for the authorization cache so they
+// can be surfaced on a metrics endpoint.
+var CacheMetrics = &cacheMetrics{}
+
+type cacheEntry struct {
+	key       string
+	allowed   bool
+	expiresAt time.Time
+}
+
+// authorizationCache is a short-lived, size-bounded, in-memory LRU cache of
+// authorization decisions keyed by a hash of the token plus the resource being
+// checked. order tracks recency, most-recently-used at the front, so a full cache
+// can evict the right entry in O(1).
+type authorizationCache struct {
+	mux     sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+func newAuthorizationCache() *authorizationCache {
+	return &authorizationCache{
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+func (c *authorizationCache) get(key string) (bool, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		CacheMetrics.recordMiss()
+		return false, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		CacheMetrics.recordMiss()
+		return false, false
+	}
+
+	c.order.MoveToFront(elem)
+	CacheMetrics.recordHit()
+	return entry.allowed, true
+}
+
+func (c *authorizationCache) set(key string, allowed bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.allowed = allowed
+		entry.expiresAt = time.Now().Add(authCacheTTL)
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	elem := c.order.PushFront(&cacheEntry{
+		key:       key,
+		allowed:   allowed,
+		expiresAt: time.Now().Add(authCacheTTL),
+	})
+	c.entries[key] = elem
+
+	if len(c.entries) > authCacheMaxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// invalidateToken removes every cache entry belonging to a given token. It is
+// called whenever a client's token is found to no longer be valid, so a stale
+// decision for it can never be served again.
+func (c *authorizationCache) invalidateToken(token string) {
+	prefix := tokenHash(token) + "|"
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for key, elem := range c.entries {
+		if len(key) >= len(prefix) && key[:len(prefix)] == prefix {
+			c.order.Remove(elem)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// InvalidateToken clears any cached authorization decisions for the given token.
+func InvalidateToken(token string) {
+	authCache.invalidateToken(token)
+}
+
+// tokenHash hashes a token so raw tokens are never kept in memory as cache keys.
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func cacheKey(token, namespace, verb, group, resource, resourceName string) string {
+	return fmt.Sprintf("%s|%s|%s|%s|%s|%s", tokenHash(token), namespace, verb, group, resource, resourceName)
+}
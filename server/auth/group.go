@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	v1 "github.com/onepanelio/core/pkg"
+)
+
+// GroupResolver resolves the set of groups a user belongs to, independent of which
+// AuthProvider authenticated them.
+type GroupResolver interface {
+	ResolveGroups(ctx context.Context, client *v1.Client, username string) ([]string, error)
+}
+
+// openShiftGroupResolver resolves group membership from OpenShift's
+// user.openshift.io/Group custom resource. It requires that API to be installed;
+// on a vanilla k8s cluster ListUserGroups errors, so it is only wired up for
+// TokenProvider sessions when OPENSHIFT_GROUPS_ENABLED is configured (see
+// TokenProvider.Authenticate) rather than being called unconditionally.
+type openShiftGroupResolver struct{}
+
+// DefaultGroupResolver is the GroupResolver used by CheckGroup when callers don't
+// need to override it (e.g. with the groups an OIDC/LDAP AuthProvider already resolved).
+var DefaultGroupResolver GroupResolver = &openShiftGroupResolver{}
+
+func (r *openShiftGroupResolver) ResolveGroups(ctx context.Context, client *v1.Client, username string) ([]string, error) {
+	groups, err := client.ListUserGroups(username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve groups for %q: %w", username, err)
+	}
+	return groups, nil
+}
+
+// IsUserInGroup reports whether group is present in groups.
+func IsUserInGroup(groups []string, group string) bool {
+	for _, g := range groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckGroup resolves a user's groups via resolver (DefaultGroupResolver if nil) and
+// reports whether they belong to groupName.
+func CheckGroup(ctx context.Context, client *v1.Client, resolver GroupResolver, username, groupName string) (bool, error) {
+	if resolver == nil {
+		resolver = DefaultGroupResolver
+	}
+
+	groups, err := resolver.ResolveGroups(ctx, client, username)
+	if err != nil {
+		return false, err
+	}
+
+	return IsUserInGroup(groups, groupName), nil
+}
+
+// parsePermittedGroups parses the PERMITTED_GROUPS system config value, formatted as
+// semicolon-separated "namespace=group1,group2" entries, into a namespace -> groups map.
+func parsePermittedGroups(raw string) map[string][]string {
+	result := make(map[string][]string)
+	for _, entry := range strings.Split(raw, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		namespace := strings.TrimSpace(parts[0])
+		groups := strings.Split(parts[1], ",")
+		for i := range groups {
+			groups[i] = strings.TrimSpace(groups[i])
+		}
+		result[namespace] = groups
+	}
+	return result
+}
+
+// permittedGroupsForNamespace returns the groups allowed to access namespace.
+//
+// This intentionally only looks at namespace's own PERMITTED_GROUPS entry. An
+// earlier version of this function inferred a namespace hierarchy by splitting the
+// name on "-" (e.g. "acme-team-dev" inheriting from "acme-team" and "acme"), but k8s
+// has no such naming-based hierarchy - two unrelated namespaces that happen to share
+// a hyphenated prefix (e.g. "dev-payments" and "dev-ops") would both inherit
+// whatever groups are permitted on "dev", which is a privilege escalation via
+// namespace naming rather than an actual parent/child relationship. If namespace
+// inheritance is needed, it should be driven by an explicit hierarchy source (e.g.
+// HNC subnamespace-anchor CRDs or a namespace label/annotation), not string splitting.
+func permittedGroupsForNamespace(config map[string]string, namespace string) []string {
+	permitted := parsePermittedGroups(config["PERMITTED_GROUPS"])
+	return append([]string{}, permitted[namespace]...)
+}
+
+// anyGroupPermitted reports whether any of userGroups appears in permittedGroups.
+func anyGroupPermitted(userGroups, permittedGroups []string) bool {
+	for _, permitted := range permittedGroups {
+		if IsUserInGroup(userGroups, permitted) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAuthorizedForIdentity is EvaluatePolicyForIdentity's fallback when no rule
+// matches for a caller authenticated via a signed Onepanel JWT (OIDC/LDAP logins, or
+// a token-provider login - see TokenProvider.Authenticate). client.Token there is the
+// JWT itself, not a credential k8s can authenticate, and there is no privileged
+// service-account client available here to ask k8s "as" identity either - an earlier
+// version of this function tried a non-self SubjectAccessReview over client anyway,
+// which can't authenticate and would typically lack RBAC to review other subjects
+// besides. So identity sessions fail closed instead: access must come from an
+// explicit policy rule (see RuleStore), not a k8s fallback. PERMITTED_GROUPS is still
+// consulted first so a caller outside the allow-list is turned away before that.
+func IsAuthorizedForIdentity(client *v1.Client, identity *Identity, namespace, verb, group, resource, resourceName string) (bool, error) {
+	config, err := client.GetSystemConfig()
+	if err != nil {
+		return false, err
+	}
+
+	permitted := permittedGroupsForNamespace(config, namespace)
+	if len(permitted) > 0 && !anyGroupPermitted(identity.Groups, permitted) {
+		return false, nil
+	}
+
+	log.Printf("%s denied %s:%s:%s: no policy rule matched and identity sessions have no k8s fallback", identity.Username, resource, resourceName, verb)
+	return false, nil
+}
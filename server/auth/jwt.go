@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// jwtTTL is how long an Onepanel-signed JWT remains fresh enough that isValidToken
+// can accept it without re-checking with the configured AuthProvider.
+const jwtTTL = 15 * time.Minute
+
+// onepanelClaims is the payload of a short-lived, Onepanel-signed JWT minted after a
+// successful AuthProvider.Authenticate call.
+type onepanelClaims struct {
+	Username   string   `json:"username"`
+	Groups     []string `json:"groups"`
+	Namespaces []string `json:"namespaces"`
+	jwt.StandardClaims
+}
+
+// SignJWT mints a short-lived JWT for the given identity, signed with signingKey.
+func SignJWT(identity *Identity, signingKey []byte) (string, error) {
+	claims := onepanelClaims{
+		Username:   identity.Username,
+		Groups:     identity.Groups,
+		Namespaces: identity.Namespaces,
+		StandardClaims: jwt.StandardClaims{
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: time.Now().Add(jwtTTL).Unix(),
+			Issuer:    "onepanel",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(signingKey)
+}
+
+// VerifyJWT validates a JWT minted by SignJWT and returns the Identity it carries.
+// It returns an error if the token is malformed, has an invalid signature, or is expired.
+func VerifyJWT(tokenString string, signingKey []byte) (*Identity, error) {
+	claims := &onepanelClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return signingKey, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	return &Identity{
+		Username:   claims.Username,
+		Groups:     claims.Groups,
+		Namespaces: claims.Namespaces,
+	}, nil
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/go-ldap/ldap/v3"
+	v1 "github.com/onepanelio/core/pkg"
+)
+
+// LDAPProvider authenticates users by binding against an LDAP directory: first as a
+// service account to locate the user's DN, then as the user itself to verify the
+// supplied password.
+type LDAPProvider struct {
+	host           string
+	port           int
+	useTLS         bool
+	bindDN         string
+	bindPassword   string
+	userSearchBase string
+	userFilter     string
+	groupsBase     string
+}
+
+// NewLDAPProviderFromConfig builds an LDAPProvider from system config keys: LDAP_HOST,
+// LDAP_PORT, LDAP_USE_TLS, LDAP_BIND_DN, LDAP_BIND_PASSWORD, LDAP_USER_SEARCH_BASE,
+// LDAP_USER_FILTER and LDAP_GROUPS_BASE.
+func NewLDAPProviderFromConfig(config map[string]string) (*LDAPProvider, error) {
+	host := config["LDAP_HOST"]
+	if host == "" {
+		return nil, fmt.Errorf("LDAP_HOST is required when AUTH_PROVIDER=ldap")
+	}
+
+	return &LDAPProvider{
+		host:           host,
+		port:           ldapPortOrDefault(config["LDAP_PORT"]),
+		useTLS:         config["LDAP_USE_TLS"] == "true",
+		bindDN:         config["LDAP_BIND_DN"],
+		bindPassword:   config["LDAP_BIND_PASSWORD"],
+		userSearchBase: config["LDAP_USER_SEARCH_BASE"],
+		userFilter:     configOrDefault(config, "LDAP_USER_FILTER", "(uid=%s)"),
+		groupsBase:     config["LDAP_GROUPS_BASE"],
+	}, nil
+}
+
+// Name implements AuthProvider.
+func (p *LDAPProvider) Name() string {
+	return "ldap"
+}
+
+// Authenticate implements AuthProvider by searching for the user's DN as the configured
+// bind account, then re-binding as that DN with the supplied password to verify it.
+func (p *LDAPProvider) Authenticate(ctx context.Context, client *v1.Client, credentials Credentials) (*Identity, error) {
+	conn, err := p.dial()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to LDAP server: %w", err)
+	}
+	defer conn.Close()
+
+	if err := conn.Bind(p.bindDN, p.bindPassword); err != nil {
+		return nil, fmt.Errorf("failed to bind service account: %w", err)
+	}
+
+	searchRequest := ldap.NewSearchRequest(
+		p.userSearchBase,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		fmt.Sprintf(p.userFilter, ldap.EscapeFilter(credentials.Username)),
+		[]string{"dn", "memberOf"},
+		nil,
+	)
+
+	result, err := conn.Search(searchRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for user: %w", err)
+	}
+	if len(result.Entries) != 1 {
+		return nil, fmt.Errorf("expected exactly one LDAP entry for user %q, found %d", credentials.Username, len(result.Entries))
+	}
+
+	userDN := result.Entries[0].DN
+	if err := conn.Bind(userDN, credentials.Password); err != nil {
+		return nil, fmt.Errorf("invalid credentials")
+	}
+
+	return &Identity{
+		Username: credentials.Username,
+		Groups:   result.Entries[0].GetAttributeValues("memberOf"),
+	}, nil
+}
+
+func (p *LDAPProvider) dial() (*ldap.Conn, error) {
+	address := fmt.Sprintf("%s:%d", p.host, p.port)
+	if p.useTLS {
+		return ldap.DialTLS("tcp", address, nil)
+	}
+	return ldap.Dial("tcp", address)
+}
+
+func ldapPortOrDefault(port string) int {
+	if parsed, err := strconv.Atoi(port); err == nil && parsed > 0 {
+		return parsed
+	}
+	return 389
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	v1 "github.com/onepanelio/core/pkg"
+	"golang.org/x/oauth2"
+)
+
+// OIDCProvider authenticates users via an authorization-code + PKCE exchange against
+// an external OpenID Connect issuer, verifying the returned ID token against the
+// issuer's JWKS and mapping its claims to Onepanel namespaces.
+type OIDCProvider struct {
+	oauthConfig    oauth2.Config
+	verifier       *oidc.IDTokenVerifier
+	claimsMapping  map[string]string // OIDC claim value -> Onepanel namespace
+	groupsClaim    string
+	usernameClaim  string
+}
+
+// NewOIDCProviderFromConfig builds an OIDCProvider from system config keys:
+// OIDC_ISSUER, OIDC_CLIENT_ID, OIDC_CLIENT_SECRET, OIDC_REDIRECT_URL, OIDC_GROUPS_CLAIM,
+// OIDC_USERNAME_CLAIM. Namespace mapping is read from OIDC_NAMESPACE_MAP_<claim-value>
+// entries (see namespaceMappingFromConfig).
+func NewOIDCProviderFromConfig(config map[string]string) (*OIDCProvider, error) {
+	issuer := config["OIDC_ISSUER"]
+	if issuer == "" {
+		return nil, fmt.Errorf("OIDC_ISSUER is required when AUTH_PROVIDER=oidc")
+	}
+
+	provider, err := oidc.NewProvider(context.Background(), issuer)
+	if err != nil {
+		return nil, fmt.Errorf("unable to reach OIDC issuer: %w", err)
+	}
+
+	clientID := config["OIDC_CLIENT_ID"]
+
+	return &OIDCProvider{
+		oauthConfig: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: config["OIDC_CLIENT_SECRET"],
+			RedirectURL:  config["OIDC_REDIRECT_URL"],
+			Endpoint:     provider.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "profile", "groups"},
+		},
+		verifier:      provider.Verifier(&oidc.Config{ClientID: clientID}),
+		groupsClaim:   configOrDefault(config, "OIDC_GROUPS_CLAIM", "groups"),
+		usernameClaim: configOrDefault(config, "OIDC_USERNAME_CLAIM", "email"),
+		claimsMapping: namespaceMappingFromConfig(config),
+	}, nil
+}
+
+// namespaceMappingFromConfig reads OIDC_NAMESPACE_MAP_<claim-value>=<namespace> entries
+// out of system config into a claim value -> namespace lookup.
+func namespaceMappingFromConfig(config map[string]string) map[string]string {
+	const prefix = "OIDC_NAMESPACE_MAP_"
+	mapping := make(map[string]string)
+	for key, value := range config {
+		if len(key) > len(prefix) && key[:len(prefix)] == prefix {
+			mapping[key[len(prefix):]] = value
+		}
+	}
+	return mapping
+}
+
+// Name implements AuthProvider.
+func (p *OIDCProvider) Name() string {
+	return "oidc"
+}
+
+// Authenticate implements AuthProvider by exchanging an authorization code (using the
+// PKCE code verifier supplied by the client) for tokens, then verifying the ID token.
+func (p *OIDCProvider) Authenticate(ctx context.Context, client *v1.Client, credentials Credentials) (*Identity, error) {
+	oauth2Token, err := p.oauthConfig.Exchange(ctx, credentials.Code,
+		oauth2.SetAuthURLParam("code_verifier", credentials.CodeVerifier),
+		oauth2.SetAuthURLParam("redirect_uri", credentials.RedirectURI),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange oidc code: %w", err)
+	}
+
+	rawIDToken, ok := oauth2Token.Extra("id_token").(string)
+	if !ok {
+		return nil, fmt.Errorf("oidc token response did not contain an id_token")
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify oidc id_token: %w", err)
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("failed to parse oidc claims: %w", err)
+	}
+
+	username, _ := claims[p.usernameClaim].(string)
+	groups := claimsToGroups(claims[p.groupsClaim])
+
+	namespaces := make([]string, 0, len(groups))
+	for _, group := range groups {
+		if namespace, ok := p.claimsMapping[group]; ok {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+
+	return &Identity{
+		Username:   username,
+		Groups:     groups,
+		Namespaces: namespaces,
+	}, nil
+}
+
+func claimsToGroups(value interface{}) []string {
+	raw, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	groups := make([]string, 0, len(raw))
+	for _, v := range raw {
+		if s, ok := v.(string); ok {
+			groups = append(groups, s)
+		}
+	}
+	return groups
+}
+
+func configOrDefault(config map[string]string, key, def string) string {
+	if v, ok := config[key]; ok && v != "" {
+		return v
+	}
+	return def
+}
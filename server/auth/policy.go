@@ -0,0 +1,260 @@
+package auth
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"sync"
+	"time"
+
+	v1 "github.com/onepanelio/core/pkg"
+)
+
+// Effect is the outcome a Rule grants when it matches.
+type Effect string
+
+const (
+	// EffectAllow grants the matched verb.
+	EffectAllow Effect = "allow"
+	// EffectDeny denies the matched verb, taking precedence over any allow rule
+	// matched for the same request.
+	EffectDeny Effect = "deny"
+)
+
+// SubjectKind identifies what a Rule's Subject refers to.
+type SubjectKind string
+
+const (
+	SubjectKindUser  SubjectKind = "user"
+	SubjectKindGroup SubjectKind = "group"
+	// SubjectKindServiceAccount is reserved for rules scoped to a k8s service account.
+	// No AuthProvider resolves a service-account Identity yet, so EvaluatePolicyForIdentity
+	// can never match a rule of this kind - CreateRule rejects it until one does.
+	SubjectKindServiceAccount SubjectKind = "service-account"
+)
+
+// Subject is who a Rule applies to.
+type Subject struct {
+	Kind SubjectKind
+	Name string
+}
+
+// Rule is a single fine-grained authorization rule, scoped to a namespace.
+type Rule struct {
+	UID              string
+	Namespace        string
+	Subject          Subject
+	ResourceType     string
+	ResourceNameGlob string
+	Verbs            []string
+	Effect           Effect
+}
+
+// matches reports whether the rule applies to the given subject, resource and verb.
+func (r *Rule) matches(subject Subject, resourceType, resourceName, verb string) bool {
+	if r.Subject != subject {
+		return false
+	}
+	if r.ResourceType != resourceType {
+		return false
+	}
+	if ok, err := path.Match(r.ResourceNameGlob, resourceName); err != nil || !ok {
+		return false
+	}
+
+	for _, v := range r.Verbs {
+		if v == verb {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateRule checks that rule's enum-like fields are well-formed before it is
+// persisted. EvaluatePolicy treats any Effect other than the literal "deny" as an allow
+// once a rule is matched, so an unvalidated typo like effect="alow" would silently
+// become a grant instead of being rejected.
+func ValidateRule(rule *Rule) error {
+	switch rule.Effect {
+	case EffectAllow, EffectDeny:
+	default:
+		return fmt.Errorf("invalid effect %q: must be %q or %q", rule.Effect, EffectAllow, EffectDeny)
+	}
+
+	switch rule.Subject.Kind {
+	case SubjectKindUser, SubjectKindGroup:
+	case SubjectKindServiceAccount:
+		return fmt.Errorf("subject kind %q is not yet supported", rule.Subject.Kind)
+	default:
+		return fmt.Errorf("invalid subject kind %q", rule.Subject.Kind)
+	}
+
+	if rule.Subject.Name == "" {
+		return fmt.Errorf("subject name is required")
+	}
+	if rule.ResourceType == "" {
+		return fmt.Errorf("resource type is required")
+	}
+	if rule.ResourceNameGlob == "" {
+		return fmt.Errorf("resource name glob is required")
+	}
+	if _, err := path.Match(rule.ResourceNameGlob, ""); err != nil {
+		return fmt.Errorf("invalid resource name glob %q: %w", rule.ResourceNameGlob, err)
+	}
+	if len(rule.Verbs) == 0 {
+		return fmt.Errorf("at least one verb is required")
+	}
+
+	return nil
+}
+
+// RuleStore persists policy rules in the same database/config store the rest of
+// Onepanel uses (see v1.Client).
+type RuleStore struct {
+	client *v1.Client
+}
+
+// NewRuleStore creates the RuleStore used by the policy engine.
+func NewRuleStore(client *v1.Client) *RuleStore {
+	return &RuleStore{client: client}
+}
+
+// ListRules returns every rule configured for namespace.
+func (s *RuleStore) ListRules(namespace string) ([]*Rule, error) {
+	return s.client.ListAuthorizationRules(namespace)
+}
+
+// CreateRule persists a new rule and returns it with its UID populated.
+func (s *RuleStore) CreateRule(rule *Rule) (*Rule, error) {
+	created, err := s.client.CreateAuthorizationRule(rule)
+	if err != nil {
+		return nil, err
+	}
+	policyIndex.invalidate(rule.Namespace)
+	return created, nil
+}
+
+// DeleteRule removes the rule identified by uid from namespace.
+func (s *RuleStore) DeleteRule(namespace, uid string) error {
+	if err := s.client.DeleteAuthorizationRule(namespace, uid); err != nil {
+		return err
+	}
+	policyIndex.invalidate(namespace)
+	return nil
+}
+
+// ruleIndexTTL bounds how long a namespace's rules are served from ruleIndex before
+// being re-read from the store, mirroring authCacheTTL. RuleStore's invalidate calls
+// only clear the copy held by the process that handled the write; in a multi-replica
+// deployment, other replicas would otherwise never notice a rule was created or
+// deleted. This TTL is the fallback that bounds how stale they can get.
+const ruleIndexTTL = 30 * time.Second
+
+type ruleIndexEntry struct {
+	rules     []*Rule
+	expiresAt time.Time
+}
+
+// ruleIndex is an in-memory, per-namespace cache of policy rules so the hot
+// IsAuthorized path doesn't hit the database on every call. It is invalidated by
+// RuleStore whenever a rule is created or deleted, and also expires entries after
+// ruleIndexTTL so other replicas pick up writes made elsewhere.
+type ruleIndex struct {
+	mux  sync.RWMutex
+	byNS map[string]ruleIndexEntry
+}
+
+var policyIndex = &ruleIndex{byNS: make(map[string]ruleIndexEntry)}
+
+func (idx *ruleIndex) rulesFor(store *RuleStore, namespace string) ([]*Rule, error) {
+	idx.mux.RLock()
+	entry, ok := idx.byNS[namespace]
+	idx.mux.RUnlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.rules, nil
+	}
+
+	rules, err := store.ListRules(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	idx.mux.Lock()
+	idx.byNS[namespace] = ruleIndexEntry{rules: rules, expiresAt: time.Now().Add(ruleIndexTTL)}
+	idx.mux.Unlock()
+
+	return rules, nil
+}
+
+func (idx *ruleIndex) invalidate(namespace string) {
+	idx.mux.Lock()
+	delete(idx.byNS, namespace)
+	idx.mux.Unlock()
+}
+
+// PolicyDecision is the outcome of evaluating the rule set for a request.
+type PolicyDecision int
+
+const (
+	// PolicyNoMatch means no rule matched; the decision is left to the caller.
+	PolicyNoMatch PolicyDecision = iota
+	// PolicyAllowed means at least one allow rule matched and no deny rule did.
+	PolicyAllowed
+	// PolicyDenied means a deny rule matched.
+	PolicyDenied
+)
+
+// EvaluatePolicy consults store's rules for namespace and returns the decision for
+// subject performing verb against resourceType/resourceName. Deny rules take
+// precedence over allow rules. If nothing matches, it logs a "denied ... by lack of
+// rule" line and returns PolicyNoMatch. AuthServer's only caller then denies the
+// request through IsAuthorizedForIdentity; no SAR check is made for it.
+func EvaluatePolicy(store *RuleStore, namespace string, subject Subject, resourceType, resourceName, verb string) (PolicyDecision, error) {
+	rules, err := policyIndex.rulesFor(store, namespace)
+	if err != nil {
+		return PolicyNoMatch, err
+	}
+
+	matched := false
+	for _, rule := range rules {
+		if !rule.matches(subject, resourceType, resourceName, verb) {
+			continue
+		}
+		matched = true
+		if rule.Effect == EffectDeny {
+			return PolicyDenied, nil
+		}
+	}
+	if matched {
+		return PolicyAllowed, nil
+	}
+
+	log.Printf("%s denied access to %s:%s:%s by lack of rule (%d rules found for namespace)", subject.Name, resourceType, resourceName, verb, len(rules))
+	return PolicyNoMatch, nil
+}
+
+// EvaluatePolicyForIdentity evaluates rules for identity's user subject and each of
+// its group subjects. A deny rule on any subject wins; otherwise an allow rule on any
+// subject is sufficient; otherwise PolicyNoMatch.
+func EvaluatePolicyForIdentity(store *RuleStore, namespace string, identity *Identity, resourceType, resourceName, verb string) (PolicyDecision, error) {
+	subjects := make([]Subject, 0, len(identity.Groups)+1)
+	subjects = append(subjects, Subject{Kind: SubjectKindUser, Name: identity.Username})
+	for _, group := range identity.Groups {
+		subjects = append(subjects, Subject{Kind: SubjectKindGroup, Name: group})
+	}
+
+	decision := PolicyNoMatch
+	for _, subject := range subjects {
+		result, err := EvaluatePolicy(store, namespace, subject, resourceType, resourceName, verb)
+		if err != nil {
+			return PolicyNoMatch, err
+		}
+		if result == PolicyDenied {
+			return PolicyDenied, nil
+		}
+		if result == PolicyAllowed {
+			decision = PolicyAllowed
+		}
+	}
+	return decision, nil
+}
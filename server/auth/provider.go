@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	v1 "github.com/onepanelio/core/pkg"
+)
+
+// Identity is the result of a successful authentication, regardless of which
+// AuthProvider produced it.
+type Identity struct {
+	Username   string
+	Groups     []string
+	Namespaces []string
+}
+
+// Credentials carries whatever a provider needs to authenticate a request.
+// Not every field is used by every provider: the token provider only reads
+// Username (the token itself is carried by the client), LDAP reads Username/Password,
+// and OIDC reads Code/CodeVerifier/RedirectURI.
+type Credentials struct {
+	Username     string
+	Token        string
+	Password     string
+	Code         string
+	CodeVerifier string
+	RedirectURI  string
+}
+
+// AuthProvider authenticates a Credentials and, on success, returns the Identity
+// it resolved to. Implementations are selected at runtime via the system config
+// key AUTH_PROVIDER.
+type AuthProvider interface {
+	// Name identifies the provider, e.g. for logging and system config lookups.
+	Name() string
+	// Authenticate verifies the credentials and returns the resolved identity.
+	Authenticate(ctx context.Context, client *v1.Client, credentials Credentials) (*Identity, error)
+}
+
+// ProviderFromConfig selects and constructs the AuthProvider configured in system config.
+// AUTH_PROVIDER defaults to "token", which preserves today's behavior of treating the
+// credential as a pre-hashed Onepanel token checked against k8s.
+func ProviderFromConfig(config map[string]string) (AuthProvider, error) {
+	switch config["AUTH_PROVIDER"] {
+	case "", "token":
+		return NewTokenProvider(), nil
+	case "oidc":
+		return NewOIDCProviderFromConfig(config)
+	case "ldap":
+		return NewLDAPProviderFromConfig(config)
+	default:
+		return nil, fmt.Errorf("unknown AUTH_PROVIDER %q", config["AUTH_PROVIDER"])
+	}
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+
+	v1 "github.com/onepanelio/core/pkg"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TokenProvider is the AuthProvider for today's pre-hashed Onepanel token, checked
+// for validity via a k8s SubjectAccessReview. It is the default provider and keeps
+// existing deployments working without any system config changes.
+type TokenProvider struct{}
+
+// NewTokenProvider creates a TokenProvider.
+func NewTokenProvider() *TokenProvider {
+	return &TokenProvider{}
+}
+
+// Name implements AuthProvider.
+func (p *TokenProvider) Name() string {
+	return "token"
+}
+
+// Authenticate implements AuthProvider by confirming the client (already constructed
+// from the request's token) can `get` the Onepanel-enabled namespace, then, on
+// OpenShift clusters (OPENSHIFT_GROUPS_ENABLED), resolving the caller's groups via
+// DefaultGroupResolver so PERMITTED_GROUPS and group-subject policy rules apply to
+// token-provider sessions the same way they do to OIDC/LDAP ones. DefaultGroupResolver
+// requires the OpenShift user API, so it is opt-in rather than called unconditionally -
+// on a vanilla k8s cluster it would fail every login.
+func (p *TokenProvider) Authenticate(ctx context.Context, client *v1.Client, credentials Credentials) (*Identity, error) {
+	namespaces, err := client.ListOnepanelEnabledNamespaces()
+	if err != nil {
+		if err.Error() == "Unauthorized" {
+			return nil, status.Error(codes.Unauthenticated, "Unauthenticated.")
+		}
+		return nil, err
+	}
+	if len(namespaces) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "No namespaces for onepanel setup.")
+	}
+
+	allowed, err := IsAuthorized(client, "", "get", "", "namespaces", namespaces[0].Name)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, status.Error(codes.Unauthenticated, "Unauthenticated.")
+	}
+
+	var groups []string
+	config, err := client.GetSystemConfig()
+	if err != nil {
+		return nil, err
+	}
+	if config["OPENSHIFT_GROUPS_ENABLED"] == "true" {
+		groups, err = DefaultGroupResolver.ResolveGroups(ctx, client, credentials.Username)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Identity{Username: credentials.Username, Groups: groups}, nil
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+
+	"github.com/onepanelio/core/pkg/util"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// authErrorCodeToGRPC maps a util.AuthErrorCode to the gRPC status code clients
+// actually see on the wire.
+var authErrorCodeToGRPC = map[util.AuthErrorCode]codes.Code{
+	util.AuthErrorValidationFailed: codes.InvalidArgument,
+	util.AuthErrorNoPermission:     codes.PermissionDenied,
+	util.AuthErrorUnauthenticated:  codes.Unauthenticated,
+	util.AuthErrorNotFound:         codes.NotFound,
+	util.AuthErrorAlreadyExists:    codes.AlreadyExists,
+	util.AuthErrorConflict:         codes.Aborted,
+	util.AuthErrorDeadlineExceeded: codes.DeadlineExceeded,
+	util.AuthErrorBadInput:         codes.InvalidArgument,
+	util.AuthErrorUnimplemented:    codes.Unimplemented,
+	util.AuthErrorInternal:         codes.Internal,
+	util.AuthErrorExternal:         codes.Unavailable,
+}
+
+// authErrorInfoDomain is the ErrorInfo.Domain attached to every mapped AuthError,
+// namespacing our reason codes from any other error source a client might see.
+const authErrorInfoDomain = "onepanel.io/auth"
+
+// AuthErrorUnaryInterceptor maps *util.AuthError returned by a handler to the
+// appropriate gRPC status code, attaches a google.rpc.ErrorInfo detail (reason,
+// domain, metadata) so clients can branch on Code rather than parsing Message, and
+// logs the error - with its stack trace, when available - via zap.
+func AuthErrorUnaryInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		res, err := handler(ctx, req)
+		if err == nil {
+			return res, nil
+		}
+
+		authErr, ok := err.(*util.AuthError)
+		if !ok {
+			return res, err
+		}
+
+		grpcCode, ok := authErrorCodeToGRPC[authErr.Code]
+		if !ok {
+			grpcCode = codes.Internal
+		}
+
+		logger.Error("rpc failed",
+			zap.String("method", info.FullMethod),
+			zap.String("reason", string(authErr.Code)),
+			zap.Error(authErr),
+		)
+
+		st := status.New(grpcCode, authErr.Message)
+
+		metadata := authErr.Metadata
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+
+		withDetails, detailErr := st.WithDetails(&errdetails.ErrorInfo{
+			Reason:   string(authErr.Code),
+			Domain:   authErrorInfoDomain,
+			Metadata: metadata,
+		})
+		if detailErr != nil {
+			return res, st.Err()
+		}
+
+		return res, withDetails.Err()
+	}
+}
+
+// UnaryServerOption bundles AuthErrorUnaryInterceptor into a grpc.ServerOption so it
+// can be passed straight to grpc.NewServer, e.g.:
+//
+//	grpc.NewServer(server.UnaryServerOption(logger))
+//
+// NOTE: that grpc.NewServer call is made in this service's main/bootstrap package,
+// which is not part of this package - nothing here calls UnaryServerOption. Until
+// whoever owns that bootstrap passes it (or an equivalent grpc.UnaryInterceptor
+// registration) to grpc.NewServer, *util.AuthError is never mapped to a gRPC status
+// code or ErrorInfo; callers just see whatever the default codec does with it.
+func UnaryServerOption(logger *zap.Logger) grpc.ServerOption {
+	return grpc.UnaryInterceptor(AuthErrorUnaryInterceptor(logger))
+}
@@ -7,9 +7,6 @@ import (
 	v1 "github.com/onepanelio/core/pkg"
 	"github.com/onepanelio/core/pkg/util"
 	"github.com/onepanelio/core/server/auth"
-	"github.com/pkg/errors"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 // AuthServer contains logic for checking Authorization of resources in the system
@@ -29,7 +26,7 @@ func (a *AuthServer) IsAuthorized(ctx context.Context, request *api.IsAuthorized
 	res = &api.IsAuthorizedResponse{}
 	if ctx == nil {
 		res.Authorized = false
-		return res, status.Error(codes.Unauthenticated, "Unauthenticated.")
+		return res, util.NewAuthError(util.AuthErrorUnauthenticated, "Unauthenticated.", nil)
 	}
 	//User auth check
 	client := getClient(ctx)
@@ -40,19 +37,95 @@ func (a *AuthServer) IsAuthorized(ctx context.Context, request *api.IsAuthorized
 	}
 
 	//Check the request
-	allowed, err := auth.IsAuthorized(client, request.IsAuthorized.Namespace, request.IsAuthorized.Verb, request.IsAuthorized.Group, request.IsAuthorized.Resource, request.IsAuthorized.ResourceName)
+	identity := a.identifyClient(client)
+	allowed, err := a.evaluateAuthorization(client, identity, request.IsAuthorized.Namespace, request.IsAuthorized.Verb, request.IsAuthorized.Group, request.IsAuthorized.Resource, request.IsAuthorized.ResourceName)
 	if err != nil {
 		res.Authorized = false
-		return res, util.NewUserError(codes.PermissionDenied, fmt.Sprintf("Namespace: %v, Verb: %v, Group: \"%v\", Resource: %v. Source: %v", request.IsAuthorized.Namespace, request.IsAuthorized.Verb, request.IsAuthorized.Group, request.IsAuthorized.ResourceName, err))
+		return res, util.NewAuthError(util.AuthErrorNoPermission, fmt.Sprintf("Namespace: %v, Verb: %v, Group: \"%v\", Resource: %v. Source: %v", request.IsAuthorized.Namespace, request.IsAuthorized.Verb, request.IsAuthorized.Group, request.IsAuthorized.ResourceName, err), map[string]string{
+			"namespace": request.IsAuthorized.Namespace,
+			"verb":      request.IsAuthorized.Verb,
+			"resource":  request.IsAuthorized.Resource,
+		})
 	}
 
 	res.Authorized = allowed
 	return res, nil
 }
 
+// evaluateAuthorization resolves a single authorization check for client, applying
+// namespace policy rules and the PERMITTED_GROUPS allow-list when identity is non-nil
+// (a JWT-authenticated session), and falling back to a bare k8s SelfSubjectAccessReview
+// otherwise. IsAuthorized and BatchIsAuthorized both go through this so the same check
+// can't be allowed by one RPC and denied by the other.
+func (a *AuthServer) evaluateAuthorization(client *v1.Client, identity *auth.Identity, namespace, verb, group, resource, resourceName string) (bool, error) {
+	if identity == nil {
+		return auth.IsAuthorized(client, namespace, verb, group, resource, resourceName)
+	}
+
+	decision, err := auth.EvaluatePolicyForIdentity(auth.NewRuleStore(client), namespace, identity, resource, resourceName, verb)
+	if err != nil {
+		return false, err
+	}
+
+	switch decision {
+	case auth.PolicyAllowed:
+		return true, nil
+	case auth.PolicyDenied:
+		return false, nil
+	default:
+		return auth.IsAuthorizedForIdentity(client, identity, namespace, verb, group, resource, resourceName)
+	}
+}
+
+// BatchIsAuthorized checks authorization for many resources in a single call.
+// It is equivalent to calling IsAuthorized once per item - the same policy rules and
+// PERMITTED_GROUPS allow-list apply - but evaluates the items concurrently against a
+// bounded worker pool and shares the authorization cache with the other auth RPCs, so
+// screens that need to gate many resources at once can do it in a single round trip.
+func (a *AuthServer) BatchIsAuthorized(ctx context.Context, request *api.BatchIsAuthorizedRequest) (res *api.BatchIsAuthorizedResponse, err error) {
+	if ctx == nil {
+		return nil, util.NewAuthError(util.AuthErrorUnauthenticated, "Unauthenticated.", nil)
+	}
+
+	client := getClient(ctx)
+
+	err = a.isValidToken(err, client)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := a.identifyClient(client)
+
+	checks := make([]auth.AuthorizationCheck, len(request.IsAuthorized))
+	for i, item := range request.IsAuthorized {
+		checks[i] = auth.AuthorizationCheck{
+			Namespace:    item.Namespace,
+			Verb:         item.Verb,
+			Group:        item.Group,
+			Resource:     item.Resource,
+			ResourceName: item.ResourceName,
+		}
+	}
+
+	results := auth.BatchIsAuthorized(checks, func(check auth.AuthorizationCheck) (bool, error) {
+		return a.evaluateAuthorization(client, identity, check.Namespace, check.Verb, check.Group, check.Resource, check.ResourceName)
+	})
+
+	res = &api.BatchIsAuthorizedResponse{
+		Results: make([]*api.IsAuthorizedResponse, len(results)),
+	}
+	for i, result := range results {
+		allowed := result.Allowed && result.Error == nil
+		res.Results[i] = &api.IsAuthorizedResponse{Authorized: allowed}
+	}
+
+	return res, nil
+}
+
+// IsValidToken checks that the client's token is authenticated and still usable.
 func (a *AuthServer) IsValidToken(ctx context.Context, req *api.IsValidTokenRequest) (res *api.IsValidTokenResponse, err error) {
 	if ctx == nil {
-		return nil, status.Error(codes.Unauthenticated, "Unauthenticated.")
+		return nil, util.NewAuthError(util.AuthErrorUnauthenticated, "Unauthenticated.", nil)
 	}
 
 	client := getClient(ctx)
@@ -74,46 +147,213 @@ func (a *AuthServer) IsValidToken(ctx context.Context, req *api.IsValidTokenRequ
 	return res, nil
 }
 
-// LogIn is an alias for IsValidToken. It returns a token given a username and hashed token.
+// LogIn authenticates a caller against whichever AuthProvider is configured
+// (token, OIDC or LDAP) and, on success, exchanges the resolved identity for a
+// short-lived Onepanel-signed JWT.
+//
+// That JWT is only ever verified locally (see isValidToken/identifyClient) - it is
+// never a credential k8s itself can authenticate. So authorization checks for a JWT
+// session are decided by policy rules alone: when no rule matches,
+// IsAuthorizedForIdentity denies without making any SubjectAccessReview.
 func (a *AuthServer) LogIn(ctx context.Context, req *api.LogInRequest) (res *api.LogInResponse, err error) {
-	resp, err := a.IsValidToken(ctx, &api.IsValidTokenRequest{
-		Username: req.Username,
-		Token:    req.TokenHash,
-	})
+	client := getClient(ctx)
 
+	config, err := client.GetSystemConfig()
 	if err != nil {
 		return nil, err
 	}
 
+	signingKey := config["JWT_SIGNING_KEY"]
+	if signingKey == "" {
+		return nil, util.NewAuthError(util.AuthErrorInternal, "JWT_SIGNING_KEY is not configured; LogIn cannot mint a session token.", nil)
+	}
+
+	provider, err := auth.ProviderFromConfig(config)
+	if err != nil {
+		return nil, util.NewAuthError(util.AuthErrorInternal, err.Error(), map[string]string{"provider": config["AUTH_PROVIDER"]})
+	}
+
+	identity, err := provider.Authenticate(ctx, client, auth.Credentials{
+		Username:     req.Username,
+		Token:        req.TokenHash,
+		Password:     req.Password,
+		Code:         req.Code,
+		CodeVerifier: req.CodeVerifier,
+		RedirectURI:  req.RedirectURI,
+	})
+	if err != nil {
+		return nil, util.NewAuthError(util.AuthErrorUnauthenticated, "Unauthenticated.", nil)
+	}
+
+	token, err := auth.SignJWT(identity, []byte(signingKey))
+	if err != nil {
+		return nil, util.WrapAuthError(util.AuthErrorInternal, err, nil)
+	}
+
 	res = &api.LogInResponse{
-		Domain:   "",
-		Token:    resp.Token,
-		Username: resp.Username,
+		Domain:   config["ONEPANEL_DOMAIN"],
+		Token:    token,
+		Username: identity.Username,
 	}
 
-	return
+	return res, nil
+}
+
+// policyAdminVerb gates the rule CRUD RPCs. Anyone who can "admin" a namespace's
+// authorizationrules can manage the fine-grained policy for it.
+const policyAdminVerb = "admin"
+
+// requireRuleAdmin checks that the client is allowed to manage rules in namespace.
+// It goes through evaluateAuthorization, like the rest of the authorization surface,
+// so JWT-authenticated identity sessions are checked against policy rules/
+// PERMITTED_GROUPS rather than always failing a SelfSubjectAccessReview they can't pass.
+func (a *AuthServer) requireRuleAdmin(client *v1.Client, identity *auth.Identity, namespace string) error {
+	allowed, err := a.evaluateAuthorization(client, identity, namespace, policyAdminVerb, "", "authorizationrules", "")
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return util.NewAuthError(util.AuthErrorNoPermission, "Not authorized to manage authorization rules in this namespace.", map[string]string{"namespace": namespace})
+	}
+	return nil
 }
 
+// CreateRule creates a new fine-grained authorization rule in a namespace.
+func (a *AuthServer) CreateRule(ctx context.Context, req *api.CreateRuleRequest) (*api.CreateRuleResponse, error) {
+	client := getClient(ctx)
+	if err := a.isValidToken(nil, client); err != nil {
+		return nil, err
+	}
+	if err := a.requireRuleAdmin(client, a.identifyClient(client), req.Namespace); err != nil {
+		return nil, err
+	}
+
+	rule := &auth.Rule{
+		Namespace:        req.Namespace,
+		Subject:          auth.Subject{Kind: auth.SubjectKind(req.Rule.SubjectKind), Name: req.Rule.SubjectName},
+		ResourceType:     req.Rule.ResourceType,
+		ResourceNameGlob: req.Rule.ResourceNameGlob,
+		Verbs:            req.Rule.Verbs,
+		Effect:           auth.Effect(req.Rule.Effect),
+	}
+
+	if err := auth.ValidateRule(rule); err != nil {
+		return nil, util.NewAuthError(util.AuthErrorBadInput, err.Error(), map[string]string{"namespace": req.Namespace})
+	}
+
+	created, err := auth.NewRuleStore(client).CreateRule(rule)
+	if err != nil {
+		return nil, util.WrapAuthError(util.AuthErrorInternal, err, map[string]string{"namespace": req.Namespace})
+	}
+
+	return &api.CreateRuleResponse{Rule: ruleToAPI(created)}, nil
+}
+
+// ListRules lists every fine-grained authorization rule configured for a namespace.
+func (a *AuthServer) ListRules(ctx context.Context, req *api.ListRulesRequest) (*api.ListRulesResponse, error) {
+	client := getClient(ctx)
+	if err := a.isValidToken(nil, client); err != nil {
+		return nil, err
+	}
+	if err := a.requireRuleAdmin(client, a.identifyClient(client), req.Namespace); err != nil {
+		return nil, err
+	}
+
+	rules, err := auth.NewRuleStore(client).ListRules(req.Namespace)
+	if err != nil {
+		return nil, util.WrapAuthError(util.AuthErrorInternal, err, map[string]string{"namespace": req.Namespace})
+	}
+
+	res := &api.ListRulesResponse{Rules: make([]*api.Rule, len(rules))}
+	for i, rule := range rules {
+		res.Rules[i] = ruleToAPI(rule)
+	}
+
+	return res, nil
+}
+
+// DeleteRule removes a single fine-grained authorization rule.
+func (a *AuthServer) DeleteRule(ctx context.Context, req *api.DeleteRuleRequest) (*api.DeleteRuleResponse, error) {
+	client := getClient(ctx)
+	if err := a.isValidToken(nil, client); err != nil {
+		return nil, err
+	}
+	if err := a.requireRuleAdmin(client, a.identifyClient(client), req.Namespace); err != nil {
+		return nil, err
+	}
+
+	if err := auth.NewRuleStore(client).DeleteRule(req.Namespace, req.Uid); err != nil {
+		return nil, util.WrapAuthError(util.AuthErrorInternal, err, map[string]string{"namespace": req.Namespace, "uid": req.Uid})
+	}
+
+	return &api.DeleteRuleResponse{Deleted: true}, nil
+}
+
+// ruleToAPI converts an internal auth.Rule to its wire representation.
+func ruleToAPI(rule *auth.Rule) *api.Rule {
+	return &api.Rule{
+		Uid:              rule.UID,
+		Namespace:        rule.Namespace,
+		SubjectKind:      string(rule.Subject.Kind),
+		SubjectName:      rule.Subject.Name,
+		ResourceType:     rule.ResourceType,
+		ResourceNameGlob: rule.ResourceNameGlob,
+		Verbs:            rule.Verbs,
+		Effect:           string(rule.Effect),
+	}
+}
+
+// identifyClient returns the Identity carried by client's token if it is a fresh
+// Onepanel-signed JWT, or nil if the token predates JWT-based sessions (or JWT signing
+// isn't configured). Callers that get nil should fall back to an identity-less check.
+func (a *AuthServer) identifyClient(client *v1.Client) *auth.Identity {
+	config, err := client.GetSystemConfig()
+	if err != nil || config["JWT_SIGNING_KEY"] == "" {
+		return nil
+	}
+
+	identity, err := auth.VerifyJWT(client.Token, []byte(config["JWT_SIGNING_KEY"]))
+	if err != nil {
+		return nil
+	}
+	return identity
+}
+
+// isValidToken checks that client's token is still good. If it is a fresh
+// Onepanel-signed JWT (minted by LogIn) it is verified locally without hitting k8s;
+// otherwise we fall back to the original behavior of checking namespace `get` access
+// via k8s, which also covers raw, pre-JWT Onepanel tokens.
 func (a *AuthServer) isValidToken(err error, client *v1.Client) error {
+	if config, cfgErr := client.GetSystemConfig(); cfgErr == nil && config["JWT_SIGNING_KEY"] != "" {
+		if _, jwtErr := auth.VerifyJWT(client.Token, []byte(config["JWT_SIGNING_KEY"])); jwtErr == nil {
+			return nil
+		}
+	}
+
 	namespaces, err := client.ListOnepanelEnabledNamespaces()
 	if err != nil {
 		if err.Error() == "Unauthorized" {
-			return status.Error(codes.Unauthenticated, "Unauthenticated.")
+			auth.InvalidateToken(client.Token)
+			return util.NewAuthError(util.AuthErrorUnauthenticated, "Unauthenticated.", nil)
 		}
-		return err
+		return util.WrapAuthError(util.AuthErrorExternal, err, nil)
 	}
 	if len(namespaces) == 0 {
-		return errors.New("No namespaces for onepanel setup.")
+		return util.NewAuthError(util.AuthErrorNotFound, "No namespaces for onepanel setup.", nil)
 	}
 	namespace := namespaces[0]
 
 	allowed, err := auth.IsAuthorized(client, "", "get", "", "namespaces", namespace.Name)
 	if err != nil {
-		return err
+		return util.WrapAuthError(util.AuthErrorExternal, err, map[string]string{"namespace": namespace.Name})
 	}
 
 	if !allowed {
-		return status.Error(codes.Unauthenticated, "Unauthenticated.")
+		// The token is still live but no longer authorized; purge any cached
+		// decisions for it so a permission that was revoked can't keep being
+		// served as "allowed" from the cache until authCacheTTL catches up.
+		auth.InvalidateToken(client.Token)
+		return util.NewAuthError(util.AuthErrorUnauthenticated, "Unauthenticated.", nil)
 	}
 	return nil
 }